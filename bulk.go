@@ -0,0 +1,153 @@
+package ftree
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// BuildOption configures NewFromPoints and Rebuild.
+type BuildOption func(*buildConfig)
+
+type buildConfig struct {
+	maxPointsPerLeaf int
+}
+
+// MaxPointsPerLeaf lets a leaf hold up to n Points before NewFromPoints or
+// Rebuild subdivides it further, instead of the default of one. Raising it
+// shortens the tree for tightly clustered input, at the cost of a linear
+// scan over up to n Points whenever a Search or KNN visits that leaf.
+func MaxPointsPerLeaf(n int) BuildOption {
+	return func(c *buildConfig) {
+		c.maxPointsPerLeaf = n
+	}
+}
+
+// NewFromPoints builds a balanced NTree from pts in one shot, rather than
+// through len(pts) individual Add calls. Repeated Add always subdivides
+// down to a single Point per leaf, so clustered input forces a long chain
+// of subdivisions holding one point apiece; NewFromPoints instead computes
+// each level's per-child partition with a single O(len(pts)) pass over the
+// same bitmask Add uses, and recurses across a node's 2^N children in
+// parallel up to GOMAXPROCS.
+//
+// Returns an error if center and bounds are invalid (see New), if any
+// Point in pts falls outside of them, or if MaxPointsPerLeaf was given a
+// value less than one.
+func NewFromPoints(center, bounds []float64, pts []*Point, opts ...BuildOption) (*NTree, error) {
+	cfg := buildConfig{maxPointsPerLeaf: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.maxPointsPerLeaf < 1 {
+		return nil, errors.New("MaxPointsPerLeaf must be >= 1")
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pts {
+		in, err := nt.Contains(p)
+		if err != nil {
+			return nil, err
+		}
+		if !in {
+			return nil, errors.New("Point doesn't fall within bounds of NTree.")
+		}
+	}
+	nt.maxLeaf = cfg.maxPointsPerLeaf
+	sem := make(chan struct{}, runtime.GOMAXPROCS(0))
+	if err := nt.build(pts, &cfg, sem); err != nil {
+		return nil, err
+	}
+	return nt, nil
+}
+
+// build fills a freshly constructed, childless nt (only its center and
+// bounds are set) with pts, subdividing as needed. Every point in pts is
+// assumed to already lie within nt's bounds.
+func (nt *NTree) build(pts []*Point, cfg *buildConfig, sem chan struct{}) error {
+	nt.count = uint64(len(pts))
+	if len(pts) <= cfg.maxPointsPerLeaf {
+		nt.points = pts
+		return nil
+	}
+
+	size := 1 << uint(nt.N())
+	buckets := make([][]*Point, size)
+	for _, p := range pts {
+		var target int
+		for j := range nt.center {
+			if p.Coords[j] > nt.center[j] {
+				target = setBit(target, uint(j))
+			}
+		}
+		buckets[target] = append(buckets[target], p)
+	}
+
+	nt.children = make([]*NTree, size)
+	for i := range nt.children {
+		center := make([]float64, nt.N())
+		bounds := make([]float64, nt.N())
+		for j := range center {
+			bounds[j] = nt.bounds[j] / 2.0
+			if hasBit(i, uint(j)) {
+				center[j] = nt.center[j] + bounds[j]
+			} else {
+				center[j] = nt.center[j] - bounds[j]
+			}
+		}
+		child, err := New(center, bounds)
+		if err != nil {
+			return err
+		}
+		child.maxLeaf = nt.maxLeaf
+		nt.children[i] = child
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, size)
+	for i := range nt.children {
+		i := i
+		select {
+		case sem <- struct{}{}:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				errs[i] = nt.children[i].build(buckets[i], cfg, sem)
+			}()
+		default:
+			// every worker slot is busy: build this child inline instead
+			// of blocking a goroutine on a full semaphore.
+			errs[i] = nt.children[i].build(buckets[i], cfg, sem)
+		}
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectPoints appends every Point stored anywhere under nt to out.
+func (nt *NTree) collectPoints(out *[]*Point) {
+	nt.mutex.RLock()
+	defer nt.mutex.RUnlock()
+	*out = append(*out, nt.points...)
+	for _, child := range nt.children {
+		child.collectPoints(out)
+	}
+}
+
+// Rebuild walks nt, collects every Point currently stored in it, and
+// returns a freshly balanced replacement built via NewFromPoints with the
+// same center and bounds. nt itself is left untouched, so pair Rebuild with
+// swapping out whatever reference to nt the caller was using.
+func (nt *NTree) Rebuild(opts ...BuildOption) (*NTree, error) {
+	var pts []*Point
+	nt.collectPoints(&pts)
+	return NewFromPoints(nt.Center(), nt.Bounds(), pts, opts...)
+}
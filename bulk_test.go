@@ -0,0 +1,111 @@
+package ftree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNewFromPoints(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	count := 2000
+	pts := make([]*Point, count)
+	for i := range pts {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		pts[i] = p
+	}
+
+	nt, err := NewFromPoints(center, bounds, pts, MaxPointsPerLeaf(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.Count() != uint64(count) {
+		t.Fatal("NewFromPoints tree has", nt.Count(), "points instead of", count)
+	}
+	p1 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+	}
+	found, err := nt.Search(p1, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != count {
+		t.Fatal("Search on bulk-built tree returned", len(found), "points instead of", count)
+	}
+
+	// every leaf must respect MaxPointsPerLeaf.
+	var check func(nt *NTree)
+	check = func(nt *NTree) {
+		if nt.children == nil {
+			if len(nt.Points()) > 8 {
+				t.Fatal("leaf holds", len(nt.Points()), "points, more than MaxPointsPerLeaf(8)")
+			}
+			return
+		}
+		for _, child := range nt.children {
+			check(child)
+		}
+	}
+	check(nt)
+}
+
+func TestNewFromPointsRejectsOutOfBounds(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	pts := []*Point{{Coords: []float64{5, 5}}}
+	if _, err := NewFromPoints(center, bounds, pts); err == nil {
+		t.Fatal("expected an error for a Point outside of bounds")
+	}
+}
+
+func TestNewFromPointsRejectsBadOption(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	if _, err := NewFromPoints(center, bounds, nil, MaxPointsPerLeaf(0)); err == nil {
+		t.Fatal("expected an error for MaxPointsPerLeaf(0)")
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	n := 2
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 300
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rebuilt, err := nt.Rebuild(MaxPointsPerLeaf(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rebuilt.Count() != nt.Count() {
+		t.Fatal("Rebuild produced", rebuilt.Count(), "points instead of", nt.Count())
+	}
+	if nt.Count() != uint64(count) {
+		t.Fatal("Rebuild mutated the original tree's count")
+	}
+}
@@ -0,0 +1,34 @@
+package ftree
+
+import "sync"
+
+// DataCodec turns the opaque Data payload attached to a Point into bytes
+// and back, so it can be included in MarshalBinary/WriteTo output. Data is
+// declared as *interface{} precisely so callers can attach whatever they
+// like to a Point, which means it can't be serialized without this hook.
+type DataCodec interface {
+	EncodeData(v interface{}) ([]byte, error)
+	DecodeData(b []byte) (interface{}, error)
+}
+
+var (
+	codecMutex sync.RWMutex
+	dataCodec  DataCodec
+)
+
+// RegisterDataCodec installs the DataCodec used by MarshalBinary,
+// UnmarshalBinary, WriteTo and ReadFrom to persist each Point's Data field.
+// Call it once at program startup, before serializing or deserializing any
+// tree whose Points carry Data. Points with a nil Data are unaffected and
+// may be serialized without registering a codec at all.
+func RegisterDataCodec(c DataCodec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	dataCodec = c
+}
+
+func currentDataCodec() DataCodec {
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+	return dataCodec
+}
@@ -0,0 +1,188 @@
+package ftree
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+)
+
+// knnItem is an entry in the best-first search priority queue used by KNN
+// and KNNBox. It holds either a candidate subtree (node != nil, not yet
+// expanded) or a concrete leaf Point (p != nil), ordered by dist: the
+// minimum possible distance from the query to anything inside node, or the
+// exact distance to p.
+type knnItem struct {
+	node *NTree
+	p    *Point
+	dist float64
+}
+
+// knnQueue is a min-heap of *knnItem ordered by ascending dist.
+type knnQueue []*knnItem
+
+func (q knnQueue) Len() int           { return len(q) }
+func (q knnQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q knnQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *knnQueue) Push(x interface{}) {
+	*q = append(*q, x.(*knnItem))
+}
+
+func (q *knnQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// sqDist returns the squared Euclidean distance between two coordinate
+// slices of equal length.
+func sqDist(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// boxPointSqDist returns the minimum possible squared distance from query to
+// any point contained within the bounding box described by center and
+// bounds, computed per-dimension as max(0, |query[i]-center[i]| - bounds[i])
+// and summed as squared distances.
+func boxPointSqDist(query, center, bounds []float64) float64 {
+	var sum float64
+	for i := range query {
+		d := math.Abs(query[i]-center[i]) - bounds[i]
+		if d > 0 {
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// boxBoxSqDist returns the minimum possible squared distance between a query
+// box (p1, p2, with p1[i] <= p2[i]) and the bounding box described by center
+// and bounds.
+func boxBoxSqDist(p1, p2, center, bounds []float64) float64 {
+	var sum float64
+	for i := range p1 {
+		min := center[i] - bounds[i]
+		max := center[i] + bounds[i]
+		d := math.Max(p1[i]-max, min-p2[i])
+		if d > 0 {
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// KNN visits the k Points nearest to query in ascending order of Euclidean
+// distance, invoking iter with each Point and its distance from query.
+// Traversal stops once iter returns false, after k results have been
+// yielded, or as soon as a candidate's distance exceeds the optional
+// maxDist cutoff (pass no maxDist, or a negative one, to search unbounded).
+//
+// This is a best-first traversal backed by a min-heap holding both
+// unexpanded subtrees (keyed by the minimum possible box-to-point distance)
+// and leaf Points (keyed by their exact distance), so points are produced
+// in true nearest-first order without visiting the whole tree.
+//
+// Returns an error if query's dimensionality doesn't match nt.N().
+func (nt *NTree) KNN(query []float64, k int, iter func(p *Point, dist float64) bool, maxDist ...float64) error {
+	if len(query) != nt.N() {
+		return errors.New("Query point has different dimensions than tree.")
+	}
+	cutoffSq := -1.0
+	if len(maxDist) > 0 && maxDist[0] >= 0 {
+		cutoffSq = maxDist[0] * maxDist[0]
+	}
+	if k <= 0 {
+		return nil
+	}
+	q := &knnQueue{{node: nt, dist: boxPointSqDist(query, nt.center, nt.bounds)}}
+	heap.Init(q)
+	found := 0
+	for q.Len() > 0 {
+		item := heap.Pop(q).(*knnItem)
+		if cutoffSq >= 0 && item.dist > cutoffSq {
+			break
+		}
+		if item.p != nil {
+			if !iter(item.p, math.Sqrt(item.dist)) {
+				return nil
+			}
+			found++
+			if found >= k {
+				return nil
+			}
+			continue
+		}
+		node := item.node
+		node.mutex.RLock()
+		for _, pt := range node.points {
+			heap.Push(q, &knnItem{p: pt, dist: sqDist(query, pt.Coords)})
+		}
+		for _, child := range node.children {
+			heap.Push(q, &knnItem{node: child, dist: boxPointSqDist(query, child.center, child.bounds)})
+		}
+		node.mutex.RUnlock()
+	}
+	return nil
+}
+
+// KNNBox is the bounding-box analogue of KNN: it visits the k Points
+// nearest to the bounding box defined by p1 and p2 (with p1[i] <= p2[i] for
+// every dimension) in ascending order, invoking iter with each Point
+// and its distance from the box. A Point inside the box has distance 0.
+// The optional maxDist cutoff and early-stop semantics match KNN.
+//
+// Returns an error if p1, p2's dimensionality doesn't match nt.N().
+func (nt *NTree) KNNBox(p1, p2 []float64, k int, iter func(p *Point, dist float64) bool, maxDist ...float64) error {
+	if len(p1) != len(p2) || len(p2) != nt.N() {
+		return errors.New("Bounding points have different dimensions than tree.")
+	}
+	cutoffSq := -1.0
+	if len(maxDist) > 0 && maxDist[0] >= 0 {
+		cutoffSq = maxDist[0] * maxDist[0]
+	}
+	if k <= 0 {
+		return nil
+	}
+	q := &knnQueue{{node: nt, dist: boxBoxSqDist(p1, p2, nt.center, nt.bounds)}}
+	heap.Init(q)
+	found := 0
+	for q.Len() > 0 {
+		item := heap.Pop(q).(*knnItem)
+		if cutoffSq >= 0 && item.dist > cutoffSq {
+			break
+		}
+		if item.p != nil {
+			if !iter(item.p, math.Sqrt(item.dist)) {
+				return nil
+			}
+			found++
+			if found >= k {
+				return nil
+			}
+			continue
+		}
+		node := item.node
+		node.mutex.RLock()
+		for _, pt := range node.points {
+			heap.Push(q, &knnItem{p: pt, dist: boxBoxSqDist(p1, p2, pt.Coords, zeros(len(pt.Coords)))})
+		}
+		for _, child := range node.children {
+			heap.Push(q, &knnItem{node: child, dist: boxBoxSqDist(p1, p2, child.center, child.bounds)})
+		}
+		node.mutex.RUnlock()
+	}
+	return nil
+}
+
+// zeros returns a zero-valued slice of length n, used to treat a Point as a
+// zero-volume box when computing its distance to a query box.
+func zeros(n int) []float64 {
+	return make([]float64, n)
+}
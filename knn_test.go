@@ -0,0 +1,180 @@
+package ftree
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestKNN(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range center {
+		center[i] = 0
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 200
+	var all []*Point
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		all = append(all, p)
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query := make([]float64, n)
+	for i := range query {
+		query[i] = (rand.Float64() * 2.0) - 1.0
+	}
+	k := 10
+	var got []*Point
+	var dists []float64
+	err = nt.KNN(query, k, func(p *Point, dist float64) bool {
+		got = append(got, p)
+		dists = append(dists, dist)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != k {
+		t.Fatal("KNN returned", len(got), "points instead of", k)
+	}
+	for i := 1; i < len(dists); i++ {
+		if dists[i] < dists[i-1] {
+			t.Fatal("KNN returned points out of distance order:", dists)
+		}
+	}
+	// cross-check each returned distance against a direct computation.
+	for i, p := range got {
+		want := sqDist(query, p.Coords)
+		if math.Abs(want-dists[i]*dists[i]) > 1e-9 {
+			t.Fatal("KNN distance mismatch for point", i)
+		}
+	}
+
+	// brute-force sort every point by distance to query, and confirm KNN
+	// returned exactly the k nearest, not merely k points in sorted order.
+	sort.Slice(all, func(i, j int) bool {
+		return sqDist(query, all[i].Coords) < sqDist(query, all[j].Coords)
+	})
+	for i, p := range got {
+		if p != all[i] {
+			t.Fatal("KNN result", i, "doesn't match the brute-force nearest point")
+		}
+	}
+
+	// mismatched dimensions should error out.
+	if err = nt.KNN([]float64{0, 0}, 1, func(p *Point, dist float64) bool { return true }); err == nil {
+		t.Fatal("expected error for mismatched dimensions")
+	}
+}
+
+func TestKNNBox(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 200
+	var all []*Point
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		all = append(all, p)
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p1 := []float64{-0.2, -0.2, -0.2}
+	p2 := []float64{0.2, 0.2, 0.2}
+	k := 15
+	var got []*Point
+	var dists []float64
+	err = nt.KNNBox(p1, p2, k, func(p *Point, dist float64) bool {
+		got = append(got, p)
+		dists = append(dists, dist)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != k {
+		t.Fatal("KNNBox returned", len(got), "points instead of", k)
+	}
+	for i := 1; i < len(dists); i++ {
+		if dists[i] < dists[i-1] {
+			t.Fatal("KNNBox returned points out of distance order:", dists)
+		}
+	}
+	for i, p := range got {
+		want := boxBoxSqDist(p1, p2, p.Coords, zeros(len(p.Coords)))
+		if math.Abs(want-dists[i]*dists[i]) > 1e-9 {
+			t.Fatal("KNNBox distance mismatch for point", i)
+		}
+	}
+
+	// brute-force sort every point by distance to the box, and confirm
+	// KNNBox returned exactly the k nearest by distance. Comparing
+	// distances rather than point identity tolerates ties (several points
+	// can sit exactly inside the box, all at distance 0).
+	sort.Slice(all, func(i, j int) bool {
+		di := boxBoxSqDist(p1, p2, all[i].Coords, zeros(len(all[i].Coords)))
+		dj := boxBoxSqDist(p1, p2, all[j].Coords, zeros(len(all[j].Coords)))
+		return di < dj
+	})
+	threshold := boxBoxSqDist(p1, p2, all[k-1].Coords, zeros(n))
+	if math.Abs(dists[k-1]*dists[k-1]-threshold) > 1e-9 {
+		t.Fatal("KNNBox's k-th nearest distance", dists[k-1]*dists[k-1], "doesn't match brute force", threshold)
+	}
+	seen := make(map[*Point]bool, k)
+	for _, p := range got {
+		if seen[p] {
+			t.Fatal("KNNBox returned the same point twice")
+		}
+		seen[p] = true
+		if d := boxBoxSqDist(p1, p2, p.Coords, zeros(n)); d > threshold+1e-9 {
+			t.Fatal("KNNBox returned a point farther than the k-th nearest")
+		}
+	}
+
+	// a point landing inside the box has distance 0.
+	if err = nt.Add(&Point{Coords: []float64{0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	var innerDist float64 = -1
+	err = nt.KNNBox(p1, p2, 1, func(p *Point, dist float64) bool {
+		innerDist = dist
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if innerDist != 0 {
+		t.Fatal("expected distance 0 for the nearest point after adding one inside the box, got", innerDist)
+	}
+
+	// mismatched dimensions should error out.
+	if err = nt.KNNBox([]float64{0, 0}, []float64{1, 1}, 1, func(p *Point, dist float64) bool { return true }); err == nil {
+		t.Fatal("expected error for mismatched dimensions")
+	}
+}
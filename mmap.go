@@ -0,0 +1,62 @@
+//go:build !windows
+// +build !windows
+
+package ftree
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// OpenMmap memory-maps the file at path, previously written by WriteTo or
+// MarshalBinary, and decodes the tree from the mapped pages the same way
+// ReadFrom would from a buffered read.
+//
+// This is not a lazy, zero-copy load: every node is still parsed into an
+// ordinary heap-allocated *NTree, the same preorder walk UnmarshalBinary
+// does, so the per-node allocation and parse cost isn't avoided. What
+// OpenMmap does buy over os.ReadFile followed by UnmarshalBinary is
+// skipping the extra whole-file buffer and its copy; the OS pages the
+// mapped file in directly. A true "reopen without deserialization" load
+// would need the on-disk format to store child offsets and an NTree
+// variant that reads coordinates straight out of the mapped bytes on
+// demand instead of copying them out up front -- this does neither, and
+// copies every coordinate out up front.
+//
+// The returned *NTree holds the mapping open via a finalizer, so it's
+// released once the tree becomes unreachable; there's no explicit Close,
+// in keeping with the rest of this package's garbage-collected lifetimes.
+func OpenMmap(path string) (*NTree, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return nil, errors.New("Can't mmap an empty ftree file.")
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	nt, _, err := ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	nt.mmap = data
+	runtime.SetFinalizer(nt, func(nt *NTree) {
+		syscall.Munmap(nt.mmap)
+	})
+	return nt, nil
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+// +build !windows
+
+package ftree
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMmap(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 200
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "tree.ftree")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = nt.WriteTo(f); err != nil {
+		f.Close()
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := OpenMmap(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Count() != uint64(count) {
+		t.Fatal("OpenMmap tree has", loaded.Count(), "points instead of", count)
+	}
+	p1 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+	}
+	points, err := loaded.Search(p1, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != count {
+		t.Fatal("Search on mmap-loaded tree returned", len(points), "points instead of", count)
+	}
+}
+
+func TestOpenMmapRejectsEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.ftree")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenMmap(path); err == nil {
+		t.Fatal("expected an error mmap'ing an empty file")
+	}
+}
@@ -23,9 +23,10 @@ type NTree struct {
 	// The bounding n-dimensional box for this ntree. It should always
 	// be true that origin[i] += bounds[i] contains p.coords[i].
 	center, bounds []float64
-	// Optional piece of data to associate with this node. Location may be
-	// imprecise on leaf nodes.
-	p *Point
+	// Points held directly on this node. Populated only on leaf nodes.
+	// Ordinarily holds at most one Point, the same as if it were still
+	// named p -- maxLeaf raises that cap for bulk-built trees.
+	points []*Point
 	// Slice for child storage, should be 2^n if initialized.
 	children []*NTree
 	// Used to coordinate write operations, concurrency.
@@ -33,6 +34,15 @@ type NTree struct {
 	// keep track of child point counts under each node, useful for
 	// histograms, density predictions, etc.
 	count uint64
+	// Maximum number of Points this node (and, once subdivided, each of its
+	// descendants) holds before splitting into children. Zero means the
+	// default of one, matching the original Add-only behavior. Set by
+	// NewFromPoints via MaxPointsPerLeaf.
+	maxLeaf int
+	// non-nil only on the root of a tree loaded via OpenMmap, in which case
+	// it holds the memory-mapped file the tree was decoded from and is
+	// released by a finalizer once the root becomes unreachable.
+	mmap []byte
 }
 
 // New creates an ntree root node, using N dimensional slices for
@@ -98,13 +108,27 @@ func (nt *NTree) BoundPoints() (min, max []float64) {
 	return min, max
 }
 
-// Point returns the optional data chunk associated with this NTree nodes.
-// This will return null if there's no Point on this node, which should happen
-// on any non-leaf node.
+// Point returns the first optional data chunk associated with this NTree
+// node. This will return nil if there's no Point on this node, which should
+// happen on any non-leaf node. Leaves built with a MaxPointsPerLeaf greater
+// than one may hold more than one Point; use Points to retrieve all of them.
 func (nt *NTree) Point() *Point {
 	nt.mutex.RLock()
 	defer nt.mutex.RUnlock()
-	return nt.p
+	if len(nt.points) == 0 {
+		return nil
+	}
+	return nt.points[0]
+}
+
+// Points returns every Point stored directly on this node. Leaves created
+// through ordinary Add calls hold at most one; leaves from a tree built
+// with NewFromPoints and a MaxPointsPerLeaf greater than one may hold
+// several.
+func (nt *NTree) Points() []*Point {
+	nt.mutex.RLock()
+	defer nt.mutex.RUnlock()
+	return nt.points
 }
 
 // Count returns this node's estimate of how many Points lie within it.
@@ -148,6 +172,16 @@ func setBit(n int, pos uint) int {
 	return n
 }
 
+// leafCap returns how many Points this node may hold before it must
+// subdivide: maxLeaf if set by a bulk build, otherwise the original default
+// of one.
+func (nt *NTree) leafCap() int {
+	if nt.maxLeaf <= 0 {
+		return 1
+	}
+	return nt.maxLeaf
+}
+
 // Add inserts a new Point into the NTree. Returns an error on any failure,
 // or nil.
 func (nt *NTree) Add(p *Point) error {
@@ -159,10 +193,10 @@ func (nt *NTree) Add(p *Point) error {
 		return errors.New("Point doesn't fall within bounds of NTree.")
 	}
 	nt.mutex.Lock()
-	if nt.p == nil && nt.children == nil {
+	if nt.children == nil && len(nt.points) < nt.leafCap() {
 		defer nt.mutex.Unlock()
 		// simplest case, add to current node
-		nt.p = p
+		nt.points = append(nt.points, p)
 		nt.count++
 		return nil
 	}
@@ -181,41 +215,42 @@ func (nt *NTree) Add(p *Point) error {
 		}
 		return err
 	}
-	if nt.p != nil && nt.children == nil {
-		// create children, re-add current node's Point data, then add Point p.
-		size := mathutil.ModPowUint64(2, uint64(nt.N()), mathutil.MaxInt)
-		nt.children = make([]*NTree, size)
-		// create new child nodes with correct bounds
-		for i := range nt.children {
-			// determine child dimensions
-			center := make([]float64, nt.N())
-			bounds := make([]float64, nt.N())
-			for j := range center {
-				// use bitmask of child index to determine dimension range for child.
-				// positive bit means positive range, otherwise negative range.
-				if hasBit(i, uint(j)) {
-					bounds[j] = nt.bounds[j] / 2.0
-					center[j] = nt.center[j] + bounds[j]
-				} else {
-					bounds[j] = nt.bounds[j] / 2.0
-					center[j] = nt.center[j] - bounds[j]
-				}
-			}
-			if nt.children[i], err = New(center, bounds); err != nil {
-				return err
+	// leaf is at capacity: create children, re-add its current Points, then
+	// add Point p.
+	size := mathutil.ModPowUint64(2, uint64(nt.N()), mathutil.MaxInt)
+	nt.children = make([]*NTree, size)
+	// create new child nodes with correct bounds
+	for i := range nt.children {
+		// determine child dimensions
+		center := make([]float64, nt.N())
+		bounds := make([]float64, nt.N())
+		for j := range center {
+			// use bitmask of child index to determine dimension range for child.
+			// positive bit means positive range, otherwise negative range.
+			if hasBit(i, uint(j)) {
+				bounds[j] = nt.bounds[j] / 2.0
+				center[j] = nt.center[j] + bounds[j]
+			} else {
+				bounds[j] = nt.bounds[j] / 2.0
+				center[j] = nt.center[j] - bounds[j]
 			}
 		}
-		// remove current Point data and re-add so it cascades into child nodes.
-		// need to bounce the mutex for this, prossible race condition?
-		curP := nt.p
-		nt.p = nil
-		nt.count--
-		nt.mutex.Unlock()
+		if nt.children[i], err = New(center, bounds); err != nil {
+			return err
+		}
+		nt.children[i].maxLeaf = nt.maxLeaf
+	}
+	// remove current Point data and re-add so it cascades into child nodes.
+	// need to bounce the mutex for this, prossible race condition?
+	curPoints := nt.points
+	nt.points = nil
+	nt.count -= uint64(len(curPoints))
+	nt.mutex.Unlock()
+	for _, curP := range curPoints {
 		if err = nt.Add(curP); err != nil {
 			return err
 		}
-		// now add new Point
-		return nt.Add(p)
 	}
-	return nil
+	// now add new Point
+	return nt.Add(p)
 }
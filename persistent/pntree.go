@@ -0,0 +1,463 @@
+// Package persistent provides PNTree, an immutable, applicative variant of
+// ftree.NTree. Add returns a new root sharing every unchanged subtree with
+// the previous version, in the same spirit as the applicative balanced-tree
+// pattern used by the Go compiler's internal abt package. This gives O(log
+// N) snapshotting: a caller can hold a stable *PNTree reference and run
+// concurrent readers (Search, Iter, KNN) against it while writers build new
+// versions on the side, with no locking required since nothing ever mutates
+// a PNTree already handed to a reader.
+package persistent
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/vinerr/ftree"
+)
+
+// MaxN mirrors ftree.MaxN: the maximum number of dimensions this package
+// can address, limited by the usable bits in Go's int type.
+const MaxN = 63
+
+// PNTree is an immutable bounding box in N-dimensional space, along with
+// optional data and children. Every PNTree value is safe to read from any
+// number of goroutines without synchronization, since once constructed it
+// is never mutated.
+type PNTree struct {
+	center, bounds []float64
+	p              *ftree.Point
+	children       []*PNTree
+	count          uint64
+}
+
+// New creates a PNTree root node, using N dimensional slices for the center
+// coordinates and relative bounds of the tree space, exactly as ftree.New.
+//
+// Returns an error if center and bounds don't have the same cardinality,
+// or a bounds dimension is <= 0.
+func New(center, bounds []float64) (*PNTree, error) {
+	if len(center) > MaxN {
+		return nil, errors.New("64 bit ints limit this library to <= 63 dimensions")
+	}
+	if len(center) != len(bounds) {
+		return nil, errors.New("center and bounds have mismatched lengths")
+	}
+	if len(center) == 0 {
+		return nil, errors.New("Can't have 0-dimensional ntree")
+	}
+	for i := range bounds {
+		if bounds[i] <= 0 {
+			return nil, errors.New("Dimension " + strconv.FormatInt(int64(i), 10) +
+				" has bounding size <= 0.")
+		}
+	}
+	nt := new(PNTree)
+	nt.center = center
+	nt.bounds = bounds
+	return nt, nil
+}
+
+// N returns the number of dimensions (N) for this PNTree.
+func (nt *PNTree) N() int {
+	return len(nt.center)
+}
+
+// Center returns the center coordinates for this PNTree node.
+func (nt *PNTree) Center() []float64 {
+	return nt.center
+}
+
+// Bounds returns the positive bounding dimensions from center for this
+// PNTree node.
+func (nt *PNTree) Bounds() []float64 {
+	return nt.bounds
+}
+
+// Point returns the optional data chunk associated with this PNTree node.
+// This will return nil if there's no Point on this node, which should
+// happen on any non-leaf node.
+func (nt *PNTree) Point() *ftree.Point {
+	return nt.p
+}
+
+// Count returns this node's estimate of how many Points lie within it.
+func (nt *PNTree) Count() uint64 {
+	return nt.count
+}
+
+// Contains checks if point p is within the bounds of the PNTree.
+// Returns an error if len(p) != nt.N().
+func (nt *PNTree) Contains(p *ftree.Point) (bool, error) {
+	if p == nil {
+		return false, errors.New("Point is nil")
+	}
+	if len(p.Coords) != nt.N() {
+		return false, errors.New("Point is " + strconv.FormatUint(uint64(len(p.Coords)), 10) +
+			" dimensional, PNTree is " + strconv.FormatUint(uint64(nt.N()), 10))
+	}
+	for i := range p.Coords {
+		if (nt.center[i]-nt.bounds[i]) > p.Coords[i] || (nt.center[i]+nt.bounds[i]) < p.Coords[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func hasBit(n int, pos uint) bool {
+	return n&(1<<pos) > 0
+}
+
+func setBit(n int, pos uint) int {
+	return n | (1 << pos)
+}
+
+// Add returns a new PNTree with p inserted, sharing every subtree of nt
+// that Add didn't need to change. nt itself is left untouched, so any
+// reader still holding it keeps seeing the tree exactly as it was.
+//
+// Returns an error, and a nil root, if p falls outside the bounds of nt.
+func (nt *PNTree) Add(p *ftree.Point) (*PNTree, error) {
+	in, err := nt.Contains(p)
+	if err != nil {
+		return nil, err
+	}
+	if !in {
+		return nil, errors.New("Point doesn't fall within bounds of PNTree.")
+	}
+	return nt.add(p)
+}
+
+func (nt *PNTree) add(p *ftree.Point) (*PNTree, error) {
+	switch {
+	case nt.p == nil && nt.children == nil:
+		// simplest case: an empty leaf becomes a new leaf holding p.
+		clone := *nt
+		clone.p = p
+		clone.count++
+		return &clone, nil
+
+	case nt.children != nil:
+		// recurse into the child that would contain p, and splice the
+		// resulting new child into a shallow copy of the children slice.
+		var target int
+		for j := range nt.center {
+			if p.Coords[j] > nt.center[j] {
+				target = setBit(target, uint(j))
+			}
+		}
+		newChild, err := nt.children[target].add(p)
+		if err != nil {
+			return nil, err
+		}
+		clone := *nt
+		clone.children = make([]*PNTree, len(nt.children))
+		copy(clone.children, nt.children)
+		clone.children[target] = newChild
+		clone.count = nt.count + 1
+		return &clone, nil
+
+	default:
+		// nt.p != nil && nt.children == nil: subdivide, re-adding the
+		// current node's Point before adding the new one.
+		size := 1 << uint(nt.N())
+		children := make([]*PNTree, size)
+		for i := range children {
+			center := make([]float64, nt.N())
+			bounds := make([]float64, nt.N())
+			for j := range center {
+				bounds[j] = nt.bounds[j] / 2.0
+				if hasBit(i, uint(j)) {
+					center[j] = nt.center[j] + bounds[j]
+				} else {
+					center[j] = nt.center[j] - bounds[j]
+				}
+			}
+			child, err := New(center, bounds)
+			if err != nil {
+				return nil, err
+			}
+			children[i] = child
+		}
+		base := &PNTree{center: nt.center, bounds: nt.bounds, children: children}
+		withOld, err := base.add(nt.p)
+		if err != nil {
+			return nil, err
+		}
+		return withOld.add(p)
+	}
+}
+
+// Iter runs f on nt itself and, if nt has children, on each of its
+// immediate children; deeper descendants are not visited. Since a PNTree
+// is never mutated once built, this
+// needs no locking and is safe to call concurrently with any number of
+// other readers or with writers producing later versions via Add.
+func (nt *PNTree) Iter(f func(n *PNTree)) {
+	f(nt)
+	if nt.children != nil {
+		for i := range nt.children {
+			f(nt.children[i])
+		}
+	}
+}
+
+// Search finds all Points falling within the bounding box between p1 and p2,
+// exactly as (*ftree.NTree).Search: every dimension i is assumed to have
+// p1[i] <= p2[i], and the match is inclusive of the supplied bounds.
+//
+// Returns nil, error if the length of p1, p2 don't match nt.N().
+func (nt *PNTree) Search(p1, p2 []float64) ([]*ftree.Point, error) {
+	if len(p1) != len(p2) || len(p2) != nt.N() {
+		return nil, errors.New("Bounding points have different dimensions than tree.")
+	}
+	var points []*ftree.Point
+	nt.search(p1, p2, &points)
+	return points, nil
+}
+
+func (nt *PNTree) search(p1, p2 []float64, out *[]*ftree.Point) {
+	if nt.children == nil {
+		if nt.p == nil {
+			return
+		}
+		for i := range nt.p.Coords {
+			if nt.p.Coords[i] < p1[i] || nt.p.Coords[i] > p2[i] {
+				return
+			}
+		}
+		*out = append(*out, nt.p)
+		return
+	}
+	for _, child := range nt.children {
+		skip := false
+		for i := range p1 {
+			s1 := child.center[i] - child.bounds[i]
+			s2 := child.center[i] + child.bounds[i]
+			// skip this child if outside this dimension's bounds
+			if (s1 < p1[i] && s2 < p1[i]) || (s1 > p2[i] && s2 > p2[i]) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		child.search(p1, p2, out)
+	}
+}
+
+// knnItem is an entry in the best-first search priority queue used by KNN.
+// It holds either a candidate subtree (node != nil, not yet expanded) or a
+// concrete leaf Point (p != nil), ordered by dist: the minimum possible
+// distance from the query to anything inside node, or the exact distance
+// to p. This mirrors the top-level ftree package's own KNN implementation.
+type knnItem struct {
+	node *PNTree
+	p    *ftree.Point
+	dist float64
+}
+
+// knnQueue is a min-heap of *knnItem ordered by ascending dist.
+type knnQueue []*knnItem
+
+func (q knnQueue) Len() int           { return len(q) }
+func (q knnQueue) Less(i, j int) bool { return q[i].dist < q[j].dist }
+func (q knnQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *knnQueue) Push(x interface{}) {
+	*q = append(*q, x.(*knnItem))
+}
+
+func (q *knnQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	*q = old[:n-1]
+	return item
+}
+
+// sqDist returns the squared Euclidean distance between two coordinate
+// slices of equal length.
+func sqDist(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return sum
+}
+
+// boxPointSqDist returns the minimum possible squared distance from query to
+// any point contained within the bounding box described by center and
+// bounds, computed per-dimension as max(0, |query[i]-center[i]| - bounds[i])
+// and summed as squared distances.
+func boxPointSqDist(query, center, bounds []float64) float64 {
+	var sum float64
+	for i := range query {
+		d := math.Abs(query[i]-center[i]) - bounds[i]
+		if d > 0 {
+			sum += d * d
+		}
+	}
+	return sum
+}
+
+// KNN visits the k Points nearest to query in ascending order of Euclidean
+// distance, invoking iter with each Point and its distance from query,
+// exactly as (*ftree.NTree).KNN. Traversal stops once iter returns false,
+// after k results have been yielded, or as soon as a candidate's distance
+// exceeds the optional maxDist cutoff (pass no maxDist, or a negative one,
+// to search unbounded).
+//
+// This is a best-first traversal backed by a min-heap holding both
+// unexpanded subtrees (keyed by the minimum possible box-to-point distance)
+// and leaf Points (keyed by their exact distance), so points are produced
+// in true nearest-first order without visiting the whole tree. Since a
+// PNTree is never mutated, this needs no locking.
+//
+// Returns an error if query's dimensionality doesn't match nt.N().
+func (nt *PNTree) KNN(query []float64, k int, iter func(p *ftree.Point, dist float64) bool, maxDist ...float64) error {
+	if len(query) != nt.N() {
+		return errors.New("Query point has different dimensions than tree.")
+	}
+	cutoffSq := -1.0
+	if len(maxDist) > 0 && maxDist[0] >= 0 {
+		cutoffSq = maxDist[0] * maxDist[0]
+	}
+	if k <= 0 {
+		return nil
+	}
+	q := &knnQueue{{node: nt, dist: boxPointSqDist(query, nt.center, nt.bounds)}}
+	heap.Init(q)
+	found := 0
+	for q.Len() > 0 {
+		item := heap.Pop(q).(*knnItem)
+		if cutoffSq >= 0 && item.dist > cutoffSq {
+			break
+		}
+		if item.p != nil {
+			if !iter(item.p, math.Sqrt(item.dist)) {
+				return nil
+			}
+			found++
+			if found >= k {
+				return nil
+			}
+			continue
+		}
+		node := item.node
+		if node.p != nil {
+			heap.Push(q, &knnItem{p: node.p, dist: sqDist(query, node.p.Coords)})
+		}
+		for _, child := range node.children {
+			heap.Push(q, &knnItem{node: child, dist: boxPointSqDist(query, child.center, child.bounds)})
+		}
+	}
+	return nil
+}
+
+// Snapshot returns a stable reference to the current version of the tree.
+// Since a PNTree is never mutated in place, holding onto the returned value
+// is all a reader needs to do to keep seeing a consistent view while
+// writers keep calling Add to produce later versions.
+func (nt *PNTree) Snapshot() *PNTree {
+	return nt
+}
+
+// Diff compares nt against an earlier version old, returning the Points
+// present in nt but not old (added) and those present in old but not nt
+// (removed). Because unchanged subtrees are shared by pointer between
+// versions, Diff skips them outright instead of walking into them.
+func (nt *PNTree) Diff(old *PNTree) (added, removed []*ftree.Point) {
+	diffNodes(nt, old, &added, &removed)
+	return added, removed
+}
+
+func diffNodes(a, b *PNTree, added, removed *[]*ftree.Point) {
+	if a == b {
+		// identical subtree (by construction, not just by value), nothing
+		// underneath it changed.
+		return
+	}
+	switch {
+	case a == nil:
+		collectPoints(b, removed)
+	case b == nil:
+		collectPoints(a, added)
+	case a.children != nil && b.children != nil:
+		for i := range a.children {
+			diffNodes(a.children[i], b.children[i], added, removed)
+		}
+	default:
+		// one side subdivided relative to the other (a leaf holding a
+		// single Point became an internal node, or vice versa), so there's
+		// no positional correspondence left to recurse on. Fall back to
+		// comparing the full point sets by identity.
+		var aPts, bPts []*ftree.Point
+		collectPoints(a, &aPts)
+		collectPoints(b, &bPts)
+		inB := make(map[*ftree.Point]bool, len(bPts))
+		for _, p := range bPts {
+			inB[p] = true
+		}
+		for _, p := range aPts {
+			if inB[p] {
+				delete(inB, p)
+			} else {
+				*added = append(*added, p)
+			}
+		}
+		for p := range inB {
+			*removed = append(*removed, p)
+		}
+	}
+}
+
+func collectPoints(nt *PNTree, out *[]*ftree.Point) {
+	if nt == nil {
+		return
+	}
+	if nt.p != nil {
+		*out = append(*out, nt.p)
+	}
+	for _, child := range nt.children {
+		collectPoints(child, out)
+	}
+}
+
+// Builder batch-inserts Points into an initial PNTree, threading the
+// returned root from each Add into the next call instead of discarding the
+// intermediate versions the way independent top-level Add calls would.
+type Builder struct {
+	root *PNTree
+	err  error
+}
+
+// NewBuilder starts a Builder from an initial root, typically a freshly
+// constructed, empty PNTree.
+func NewBuilder(root *PNTree) *Builder {
+	return &Builder{root: root}
+}
+
+// Add inserts p into the Builder's current root. Once Add has returned an
+// error, the Builder keeps returning it on every subsequent call.
+func (b *Builder) Add(p *ftree.Point) error {
+	if b.err != nil {
+		return b.err
+	}
+	root, err := b.root.Add(p)
+	if err != nil {
+		b.err = err
+		return err
+	}
+	b.root = root
+	return nil
+}
+
+// Build returns the PNTree resulting from every successful Add call made
+// so far.
+func (b *Builder) Build() *PNTree {
+	return b.root
+}
@@ -0,0 +1,286 @@
+package persistent
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/vinerr/ftree"
+)
+
+func TestPNTreeAddSharesSubtrees(t *testing.T) {
+	n := 2
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range center {
+		bounds[i] = 1
+	}
+	v0, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p1 := &ftree.Point{Coords: []float64{0.5, 0.5}}
+	v1, err := v0.Add(p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v0.Count() != 0 {
+		t.Fatal("Add mutated the previous version, v0.Count() =", v0.Count())
+	}
+	if v1.Count() != 1 {
+		t.Fatal("expected v1.Count() == 1, got", v1.Count())
+	}
+
+	p2 := &ftree.Point{Coords: []float64{-0.5, -0.5}}
+	v2, err := v1.Add(p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Count() != 1 {
+		t.Fatal("Add mutated v1, v1.Count() =", v1.Count())
+	}
+	if v2.Count() != 2 {
+		t.Fatal("expected v2.Count() == 2, got", v2.Count())
+	}
+	// v1 was a single leaf holding p1, so this Add had to subdivide it;
+	// v2.children now exist where v1 had none.
+	if v1.children != nil {
+		t.Fatal("expected v1 to remain an unsubdivided leaf")
+	}
+
+	// a third point, landing in a quadrant of its own, should share v2's
+	// other two (now-populated) children by pointer with v3.
+	p3 := &ftree.Point{Coords: []float64{0.5, -0.5}}
+	v3, err := v2.Add(p3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v3.Count() != 3 {
+		t.Fatal("expected v3.Count() == 3, got", v3.Count())
+	}
+	var shared bool
+	for i := range v2.children {
+		if v2.children[i] == v3.children[i] && v2.children[i].count > 0 {
+			shared = true
+		}
+	}
+	if !shared {
+		t.Fatal("expected v2 and v3 to share an unchanged, populated subtree")
+	}
+}
+
+func TestPNTreeDiff(t *testing.T) {
+	n := 2
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range center {
+		bounds[i] = 1
+	}
+	root, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder(root)
+	var pts []*ftree.Point
+	for i := 0; i < 20; i++ {
+		p := &ftree.Point{Coords: []float64{
+			(rand.Float64() * 2.0) - 1.0,
+			(rand.Float64() * 2.0) - 1.0,
+		}}
+		pts = append(pts, p)
+		if err := b.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v1 := b.Build()
+
+	extra := &ftree.Point{Coords: []float64{0.1, 0.1}}
+	v2, err := v1.Add(extra)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed := v2.Diff(v1)
+	if len(removed) != 0 {
+		t.Fatal("expected no removed points, got", len(removed))
+	}
+	if len(added) != 1 || added[0] != extra {
+		t.Fatal("expected Diff to report exactly the newly added point")
+	}
+
+	if added, removed := v1.Diff(v1); len(added) != 0 || len(removed) != 0 {
+		t.Fatal("expected no diff against self")
+	}
+}
+
+func TestPNTreeIter(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	root, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two points in different quadrants force a subdivision.
+	v1, err := root.Add(&ftree.Point{Coords: []float64{0.5, 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := v1.Add(&ftree.Point{Coords: []float64{-0.5, -0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2.children == nil {
+		t.Fatal("test setup expected v2 to have subdivided")
+	}
+
+	// Iter, like ftree.NTree's, only visits a node and its immediate
+	// children -- not the whole subtree.
+	var visited int
+	v2.Iter(func(n *PNTree) {
+		visited++
+	})
+	if want := 1 + len(v2.children); visited != want {
+		t.Fatal("Iter visited", visited, "nodes, want", want)
+	}
+}
+
+func TestPNTreeSearch(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	root, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder(root)
+	count := 300
+	for i := 0; i < count; i++ {
+		p := &ftree.Point{Coords: make([]float64, n)}
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		if err := b.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nt := b.Build()
+
+	// cross-check against collectPoints, a full recursive walk, since
+	// Iter (like ftree.NTree's) only visits a node and its immediate
+	// children rather than the whole subtree.
+	var all []*ftree.Point
+	collectPoints(nt, &all)
+	if len(all) != count {
+		t.Fatal("collectPoints found", len(all), "points, want", count)
+	}
+
+	p1 := make([]float64, n)
+	p2 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+		p2[i] = 1
+	}
+	found, err := nt.Search(p1, p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != count {
+		t.Fatal("Search returned", len(found), "points, want", count)
+	}
+
+	half := make([]float64, n)
+	for i := range half {
+		half[i] = 0
+	}
+	inHalf, err := nt.Search(p1, half)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want int
+	for _, p := range all {
+		inBounds := true
+		for i := range p.Coords {
+			if p.Coords[i] < p1[i] || p.Coords[i] > half[i] {
+				inBounds = false
+				break
+			}
+		}
+		if inBounds {
+			want++
+		}
+	}
+	if len(inHalf) != want {
+		t.Fatal("Search found", len(inHalf), "points in the half-space, want", want)
+	}
+
+	if _, err = nt.Search([]float64{0, 0}, []float64{1, 1}); err == nil {
+		t.Fatal("expected an error for mismatched dimensions")
+	}
+}
+
+func TestPNTreeKNN(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	root, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewBuilder(root)
+	count := 200
+	var all []*ftree.Point
+	for i := 0; i < count; i++ {
+		p := &ftree.Point{Coords: make([]float64, n)}
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		all = append(all, p)
+		if err := b.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	nt := b.Build()
+
+	query := make([]float64, n)
+	for i := range query {
+		query[i] = (rand.Float64() * 2.0) - 1.0
+	}
+	k := 10
+
+	var got []*ftree.Point
+	if err := nt.KNN(query, k, func(p *ftree.Point, dist float64) bool {
+		got = append(got, p)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != k {
+		t.Fatal("KNN returned", len(got), "points instead of", k)
+	}
+
+	// brute-force sort every point by distance to query, and compare the
+	// first k against what KNN returned.
+	sort.Slice(all, func(i, j int) bool {
+		return sqDist(query, all[i].Coords) < sqDist(query, all[j].Coords)
+	})
+	for i, p := range got {
+		if p != all[i] {
+			t.Fatal("KNN result", i, "doesn't match the brute-force nearest point")
+		}
+	}
+
+	if err := nt.KNN([]float64{0, 0}, 1, func(p *ftree.Point, dist float64) bool { return true }); err == nil {
+		t.Fatal("expected an error for mismatched dimensions")
+	}
+}
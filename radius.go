@@ -0,0 +1,60 @@
+package ftree
+
+import "errors"
+
+// SearchRadius finds all Points within r of center (a hyperspherical query,
+// as opposed to Search's axis-aligned box). Child subtrees are pruned using
+// the same minimum box-to-point distance KNN uses, so this doesn't degrade
+// to a full scan of every box-matching candidate.
+//
+// Returns an error if center's dimensionality doesn't match nt.N(), or if
+// r is negative.
+func (nt *NTree) SearchRadius(center []float64, r float64) ([]*Point, error) {
+	if len(center) != nt.N() {
+		return nil, errors.New("Center point has different dimensions than tree.")
+	}
+	if r < 0 {
+		return nil, errors.New("Radius must be >= 0.")
+	}
+	var out []*Point
+	nt.searchRadius(center, r*r, &out)
+	return out, nil
+}
+
+func (nt *NTree) searchRadius(center []float64, rSq float64, out *[]*Point) {
+	nt.mutex.RLock()
+	defer nt.mutex.RUnlock()
+	if nt.children == nil {
+		for _, pt := range nt.points {
+			if sqDist(center, pt.Coords) <= rSq {
+				*out = append(*out, pt)
+			}
+		}
+		return
+	}
+	for _, child := range nt.children {
+		if boxPointSqDist(center, child.center, child.bounds) <= rSq {
+			child.searchRadius(center, rSq, out)
+		}
+	}
+}
+
+// SearchFunc finds all Points within the bounding box between p1 and p2,
+// exactly as Search, but additionally keeps only the ones for which keep
+// returns true. keep is only ever called on candidates the bounding box
+// has already accepted, not on every Point in the tree.
+//
+// Returns an error if the length of p1, p2 don't match nt.N().
+func (nt *NTree) SearchFunc(p1, p2 []float64, keep func(p *Point) bool) ([]*Point, error) {
+	found, err := nt.Search(p1, p2)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Point, 0, len(found))
+	for _, p := range found {
+		if keep(p) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
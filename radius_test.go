@@ -0,0 +1,168 @@
+package ftree
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestSearchRadius(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 500
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := make([]float64, n)
+	r := 0.4
+	found, err := nt.SearchRadius(query, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// cross-check against a brute-force box search plus a manual distance
+	// filter.
+	p1 := make([]float64, n)
+	p2 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+		p2[i] = 1
+	}
+	all, err := nt.Search(p1, p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want int
+	for _, p := range all {
+		if math.Sqrt(sqDist(query, p.Coords)) <= r {
+			want++
+		}
+	}
+	if len(found) != want {
+		t.Fatal("SearchRadius found", len(found), "points, want", want)
+	}
+	for _, p := range found {
+		if math.Sqrt(sqDist(query, p.Coords)) > r {
+			t.Fatal("SearchRadius returned a point outside the radius")
+		}
+	}
+
+	if _, err = nt.SearchRadius(query, -1); err == nil {
+		t.Fatal("expected an error for a negative radius")
+	}
+	if _, err = nt.SearchRadius([]float64{0, 0}, 1); err == nil {
+		t.Fatal("expected an error for mismatched dimensions")
+	}
+}
+
+func TestSearchFunc(t *testing.T) {
+	n := 2
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 200
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	onlyPositiveX := func(p *Point) bool {
+		return p.Coords[0] > 0
+	}
+	found, err := nt.SearchFunc([]float64{-1, -1}, []float64{1, 1}, onlyPositiveX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range found {
+		if p.Coords[0] <= 0 {
+			t.Fatal("SearchFunc returned a point that fails the predicate")
+		}
+	}
+
+	all, err := nt.Search([]float64{-1, -1}, []float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want int
+	for _, p := range all {
+		if onlyPositiveX(p) {
+			want++
+		}
+	}
+	if len(found) != want {
+		t.Fatal("SearchFunc returned", len(found), "points, want", want)
+	}
+}
+
+func TestSearchBoxPruning(t *testing.T) {
+	// regression test for a bug where Search's per-child pruning check
+	// never actually skipped a child: it only `continue`d the inner
+	// per-dimension loop, so every child was always descended into.
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	near := &Point{Coords: []float64{0.1, 0.1}}
+	far := &Point{Coords: []float64{-0.9, -0.9}}
+	if err = nt.Add(near); err != nil {
+		t.Fatal(err)
+	}
+	if err = nt.Add(far); err != nil {
+		t.Fatal(err)
+	}
+	// the result set alone can't show whether a child was pruned, so hold
+	// the write lock of the child covering far, which lies wholly outside
+	// the query box below: if Search descends into it, its RLock blocks.
+	pruned := nt.children[0]
+	pruned.mutex.Lock()
+	defer pruned.mutex.Unlock()
+	type result struct {
+		found []*Point
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		found, err := nt.Search([]float64{0.05, 0.05}, []float64{1, 1})
+		done <- result{found, err}
+	}()
+	var res result
+	select {
+	case res = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Search descended into a child lying wholly outside the query box")
+	}
+	if res.err != nil {
+		t.Fatal(res.err)
+	}
+	if len(res.found) != 1 || res.found[0] != near {
+		t.Fatal("Search should only return points within the query box")
+	}
+}
@@ -0,0 +1,168 @@
+package ftree
+
+import "errors"
+
+// Remove locates the leaf holding p, by pointer identity, clears it, and
+// decrements count up the ancestor chain. Any ancestor whose subtree drops
+// to leafCap() Points or fewer (one, by default) is collapsed back into a
+// single leaf, the same shape Add would have produced for that many Points.
+//
+// Returns an error if p is nil, falls outside nt's bounds, or isn't
+// currently stored in the tree.
+func (nt *NTree) Remove(p *Point) error {
+	if p == nil {
+		return errors.New("Point is nil")
+	}
+	in, err := nt.Contains(p)
+	if err != nil {
+		return err
+	}
+	if !in {
+		return errors.New("Point doesn't fall within bounds of NTree.")
+	}
+	removed, err := nt.remove(p)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return errors.New("Point not found in NTree.")
+	}
+	return nil
+}
+
+func (nt *NTree) remove(p *Point) (bool, error) {
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+	if nt.children == nil {
+		for i, pt := range nt.points {
+			if pt == p {
+				nt.points = append(nt.points[:i], nt.points[i+1:]...)
+				nt.count--
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	// recurse into the child that would contain p, same bitmask Add uses.
+	var target int
+	for j := range nt.center {
+		if p.Coords[j] > nt.center[j] {
+			target = setBit(target, uint(j))
+		}
+	}
+	removed, err := nt.children[target].remove(p)
+	if err != nil || !removed {
+		return removed, err
+	}
+	nt.count--
+	if nt.count <= uint64(nt.leafCap()) {
+		nt.collapse()
+	}
+	return true, nil
+}
+
+// collapse gathers every Point under nt, which must currently have
+// children, back onto nt itself and discards the children, turning an
+// under-populated internal node back into a leaf.
+func (nt *NTree) collapse() {
+	var pts []*Point
+	for _, child := range nt.children {
+		child.collectPoints(&pts)
+	}
+	nt.children = nil
+	nt.points = pts
+}
+
+// Move relocates p to newCoords. If newCoords still fall within the
+// bounds of the leaf currently holding p, this is a cheap in-place update;
+// otherwise it's equivalent to Remove followed by Add with the updated
+// Point.
+//
+// Returns an error if p isn't currently stored in the tree, if newCoords
+// has different dimensionality than nt, or if newCoords falls outside of
+// nt's overall bounds. In every error case the tree and p are left exactly
+// as they were: Move either fully succeeds or has no effect.
+func (nt *NTree) Move(p *Point, newCoords []float64) error {
+	if p == nil {
+		return errors.New("Point is nil")
+	}
+	if len(newCoords) != nt.N() {
+		return errors.New("newCoords has different dimensions than tree.")
+	}
+	moved := &Point{Coords: newCoords}
+	in, err := nt.Contains(moved)
+	if err != nil {
+		return err
+	}
+	if !in {
+		return errors.New("newCoords doesn't fall within bounds of NTree.")
+	}
+	leaf, err := nt.locate(p)
+	if err != nil {
+		return err
+	}
+	if leaf == nil {
+		return errors.New("Point not found in NTree.")
+	}
+
+	leaf.mutex.Lock()
+	inLeaf := true
+	for i := range newCoords {
+		if (leaf.center[i]-leaf.bounds[i]) > newCoords[i] || (leaf.center[i]+leaf.bounds[i]) < newCoords[i] {
+			inLeaf = false
+			break
+		}
+	}
+	if inLeaf {
+		p.Coords = newCoords
+		leaf.mutex.Unlock()
+		return nil
+	}
+	leaf.mutex.Unlock()
+
+	// newCoords is already confirmed to be within nt's overall bounds, so
+	// the Add below can't fail on that account; only remove p once we know
+	// the reinsertion will succeed.
+	oldCoords := p.Coords
+	if err := nt.Remove(p); err != nil {
+		return err
+	}
+	p.Coords = newCoords
+	if err := nt.Add(p); err != nil {
+		p.Coords = oldCoords
+		nt.Add(p)
+		return err
+	}
+	return nil
+}
+
+// locate returns the leaf node holding p, by pointer identity, or nil if p
+// isn't found anywhere under nt.
+func (nt *NTree) locate(p *Point) (*NTree, error) {
+	in, err := nt.Contains(p)
+	if err != nil {
+		return nil, err
+	}
+	if !in {
+		return nil, nil
+	}
+	nt.mutex.RLock()
+	if nt.children == nil {
+		defer nt.mutex.RUnlock()
+		for _, pt := range nt.points {
+			if pt == p {
+				return nt, nil
+			}
+		}
+		return nil, nil
+	}
+	var target int
+	for j := range nt.center {
+		if p.Coords[j] > nt.center[j] {
+			target = setBit(target, uint(j))
+		}
+	}
+	child := nt.children[target]
+	nt.mutex.RUnlock()
+	return child.locate(p)
+}
@@ -0,0 +1,145 @@
+package ftree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range bounds {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 300
+	pts := make([]*Point, count)
+	for i := range pts {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		pts[i] = p
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if err = nt.Remove(pts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if nt.Count() != uint64(count-100) {
+		t.Fatal("Count() is", nt.Count(), "after removing 100 points, want", count-100)
+	}
+
+	p1 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+	}
+	found, err := nt.Search(p1, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != count-100 {
+		t.Fatal("Search found", len(found), "points after removal, want", count-100)
+	}
+	for _, removedPt := range pts[:100] {
+		for _, f := range found {
+			if f == removedPt {
+				t.Fatal("Search returned a Point that was Removed")
+			}
+		}
+	}
+
+	// removing something not in the tree is an error.
+	stray := &Point{Coords: []float64{0, 0, 0}}
+	if err = nt.Remove(stray); err == nil {
+		t.Fatal("expected an error removing a Point never added to the tree")
+	}
+}
+
+func TestMove(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Point{Coords: []float64{0.9, 0.9}}
+	if err = nt.Add(p); err != nil {
+		t.Fatal(err)
+	}
+	other := &Point{Coords: []float64{-0.9, -0.9}}
+	if err = nt.Add(other); err != nil {
+		t.Fatal(err)
+	}
+
+	// a small move that stays within the same leaf's bounds.
+	if err = nt.Move(p, []float64{0.8, 0.8}); err != nil {
+		t.Fatal(err)
+	}
+	if p.Coords[0] != 0.8 || p.Coords[1] != 0.8 {
+		t.Fatal("Move didn't update p.Coords in place")
+	}
+	if nt.Count() != 2 {
+		t.Fatal("in-place Move changed Count(), got", nt.Count())
+	}
+
+	// a move far enough to land in a different leaf.
+	if err = nt.Move(p, []float64{-0.8, -0.8}); err != nil {
+		t.Fatal(err)
+	}
+	if nt.Count() != 2 {
+		t.Fatal("cross-leaf Move changed Count(), got", nt.Count())
+	}
+	found, err := nt.Search([]float64{-1, -1}, []float64{0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var seen bool
+	for _, f := range found {
+		if f == p {
+			seen = true
+		}
+	}
+	if !seen {
+		t.Fatal("Search after Move didn't find p at its new location")
+	}
+}
+
+func TestMoveOutOfBoundsLeavesTreeUnchanged(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Point{Coords: []float64{0.5, 0.5}}
+	if err = nt.Add(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = nt.Move(p, []float64{5, 5}); err == nil {
+		t.Fatal("expected an error moving a Point outside the tree's bounds")
+	}
+	if nt.Count() != 1 {
+		t.Fatal("Move dropped a Point from the tree on failure, Count() =", nt.Count())
+	}
+	if p.Coords[0] != 0.5 || p.Coords[1] != 0.5 {
+		t.Fatal("Move mutated p.Coords on failure, got", p.Coords)
+	}
+	found, err := nt.Search([]float64{-1, -1}, []float64{1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0] != p {
+		t.Fatal("Point is no longer findable in the tree after a failed Move")
+	}
+}
@@ -27,27 +27,37 @@ func (nt *NTree) Search(p1, p2 []float64) (points []*Point, err error) {
 	if len(p1) != len(p2) || len(p2) != nt.N() {
 		return nil, errors.New("Bounding points have different dimensions than tree.")
 	}
-	if nt.children == nil && nt.p != nil {
-		// check local Point for leaf node
-		for i := range nt.p.Coords {
-			if nt.p.Coords[i] < p1[i] || nt.p.Coords[i] > p2[i] {
-				return nil, nil
+	if nt.children == nil && len(nt.points) > 0 {
+		// check local Points for leaf node
+		points = make([]*Point, 0, len(nt.points))
+	nextPoint:
+		for _, pt := range nt.points {
+			for i := range pt.Coords {
+				if pt.Coords[i] < p1[i] || pt.Coords[i] > p2[i] {
+					continue nextPoint
+				}
 			}
+			points = append(points, pt)
 		}
-		return []*Point{nt.p}, nil
+		return points, nil
 	}
 	if nt.children != nil {
 		// check children for matching bounds, and collect matching points from them.
 		points = make([]*Point, 0)
 		for _, child := range nt.children {
+			skip := false
 			for i := range p1 {
 				s1 := child.center[i] - child.bounds[i]
 				s2 := child.center[i] + child.bounds[i]
 				// skip this child if outside this dimension's bounds
 				if (s1 < p1[i] && s2 < p1[i]) || (s1 > p2[i] && s2 > p2[i]) {
-					continue
+					skip = true
+					break
 				}
 			}
+			if skip {
+				continue
+			}
 			p, err := child.Search(p1, p2)
 			if err != nil {
 				return nil, err
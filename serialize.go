@@ -0,0 +1,298 @@
+package ftree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// On-disk format: a versioned header (magic, version, dimension count,
+// center, bounds) followed by a preorder walk of the tree's topology. Each
+// node writes its count, its maxLeaf as a uint32, and a one-byte marker
+// (empty / leaf / internal);
+// child center and bounds are never written, since they're always exactly
+// derivable from the parent the same way Add subdivides a node.
+const (
+	formatMagic = "FTR1"
+	// formatVersion 2 added support for leaves holding more than one
+	// Point (see NewFromPoints' MaxPointsPerLeaf); a leaf node now writes
+	// a Point count ahead of its Point data instead of exactly one.
+	// formatVersion 3 persists each node's maxLeaf alongside its count, so
+	// a MaxPointsPerLeaf tree keeps its leaf capacity across a round trip.
+	formatVersion = uint8(3)
+
+	nodeEmpty    = byte(0)
+	nodeLeaf     = byte(1)
+	nodeInternal = byte(2)
+)
+
+// MarshalBinary encodes the full tree rooted at nt -- center, bounds,
+// count, children topology, point coordinates and each Point's Data
+// payload (via the registered DataCodec) -- into the format described
+// above.
+func (nt *NTree) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := nt.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary replaces nt's contents with the tree encoded in data, as
+// produced by MarshalBinary.
+func (nt *NTree) UnmarshalBinary(data []byte) error {
+	root, _, err := ReadFrom(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	nt.center = root.center
+	nt.bounds = root.bounds
+	nt.points = root.points
+	nt.children = root.children
+	nt.count = root.count
+	nt.maxLeaf = root.maxLeaf
+	return nil
+}
+
+// WriteTo streams the same format MarshalBinary produces to w, returning
+// the number of bytes written.
+func (nt *NTree) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	if _, err := io.WriteString(cw, formatMagic); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, formatVersion); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, uint8(nt.N())); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, nt.center); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, nt.bounds); err != nil {
+		return cw.n, err
+	}
+	if err := nt.writeNode(cw); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+func (nt *NTree) writeNode(w io.Writer) error {
+	nt.mutex.RLock()
+	defer nt.mutex.RUnlock()
+	if err := binary.Write(w, binary.LittleEndian, nt.count); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(nt.maxLeaf)); err != nil {
+		return err
+	}
+	switch {
+	case nt.children != nil:
+		if _, err := w.Write([]byte{nodeInternal}); err != nil {
+			return err
+		}
+		for _, child := range nt.children {
+			if err := child.writeNode(w); err != nil {
+				return err
+			}
+		}
+	case len(nt.points) > 0:
+		if _, err := w.Write([]byte{nodeLeaf}); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(nt.points))); err != nil {
+			return err
+		}
+		for _, pt := range nt.points {
+			if err := binary.Write(w, binary.LittleEndian, pt.Coords); err != nil {
+				return err
+			}
+			if err := writeData(w, pt.Data); err != nil {
+				return err
+			}
+		}
+	default:
+		if _, err := w.Write([]byte{nodeEmpty}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeData persists a Point's Data payload as a length-prefixed blob: a
+// uint32 tag of 0 means no Data, otherwise the tag is len(payload)+1.
+func writeData(w io.Writer, data *interface{}) error {
+	if data == nil {
+		return binary.Write(w, binary.LittleEndian, uint32(0))
+	}
+	codec := currentDataCodec()
+	if codec == nil {
+		return errors.New("Point has Data set but no DataCodec is registered, call RegisterDataCodec first.")
+	}
+	b, err := codec.EncodeData(*data)
+	if err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(b))+1); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ReadFrom reconstructs a tree previously written by WriteTo, reading from
+// r and returning the new root along with the number of bytes consumed.
+// Unlike io.ReaderFrom, this builds a brand new *NTree rather than reading
+// into an existing one, since the persisted header fully determines the
+// tree's center and bounds.
+func ReadFrom(r io.Reader) (*NTree, int64, error) {
+	cr := &countingReader{r: r}
+	magic := make([]byte, len(formatMagic))
+	if _, err := io.ReadFull(cr, magic); err != nil {
+		return nil, cr.n, err
+	}
+	if string(magic) != formatMagic {
+		return nil, cr.n, errors.New("Data does not start with a recognized ftree header.")
+	}
+	var version uint8
+	if err := binary.Read(cr, binary.LittleEndian, &version); err != nil {
+		return nil, cr.n, err
+	}
+	if version != formatVersion {
+		return nil, cr.n, errors.New("Unsupported ftree format version " + strconv.FormatUint(uint64(version), 10))
+	}
+	var n uint8
+	if err := binary.Read(cr, binary.LittleEndian, &n); err != nil {
+		return nil, cr.n, err
+	}
+	center := make([]float64, n)
+	if err := binary.Read(cr, binary.LittleEndian, center); err != nil {
+		return nil, cr.n, err
+	}
+	bounds := make([]float64, n)
+	if err := binary.Read(cr, binary.LittleEndian, bounds); err != nil {
+		return nil, cr.n, err
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		return nil, cr.n, err
+	}
+	if err := nt.readNode(cr); err != nil {
+		return nil, cr.n, err
+	}
+	return nt, cr.n, nil
+}
+
+func (nt *NTree) readNode(r io.Reader) error {
+	if err := binary.Read(r, binary.LittleEndian, &nt.count); err != nil {
+		return err
+	}
+	var maxLeaf uint32
+	if err := binary.Read(r, binary.LittleEndian, &maxLeaf); err != nil {
+		return err
+	}
+	nt.maxLeaf = int(maxLeaf)
+	var flag [1]byte
+	if _, err := io.ReadFull(r, flag[:]); err != nil {
+		return err
+	}
+	switch flag[0] {
+	case nodeInternal:
+		size := 1 << uint(nt.N())
+		nt.children = make([]*NTree, size)
+		for i := range nt.children {
+			center := make([]float64, nt.N())
+			bounds := make([]float64, nt.N())
+			for j := range center {
+				bounds[j] = nt.bounds[j] / 2.0
+				if hasBit(i, uint(j)) {
+					center[j] = nt.center[j] + bounds[j]
+				} else {
+					center[j] = nt.center[j] - bounds[j]
+				}
+			}
+			child, err := New(center, bounds)
+			if err != nil {
+				return err
+			}
+			nt.children[i] = child
+			if err := child.readNode(r); err != nil {
+				return err
+			}
+		}
+	case nodeLeaf:
+		var pointCount uint32
+		if err := binary.Read(r, binary.LittleEndian, &pointCount); err != nil {
+			return err
+		}
+		nt.points = make([]*Point, pointCount)
+		for i := range nt.points {
+			coords := make([]float64, nt.N())
+			if err := binary.Read(r, binary.LittleEndian, coords); err != nil {
+				return err
+			}
+			data, err := readData(r)
+			if err != nil {
+				return err
+			}
+			nt.points[i] = &Point{Coords: coords, Data: data}
+		}
+	case nodeEmpty:
+		// nothing further to read
+	default:
+		return errors.New("Unrecognized node marker in ftree data.")
+	}
+	return nil
+}
+
+func readData(r io.Reader) (*interface{}, error) {
+	var tag uint32
+	if err := binary.Read(r, binary.LittleEndian, &tag); err != nil {
+		return nil, err
+	}
+	if tag == 0 {
+		return nil, nil
+	}
+	b := make([]byte, tag-1)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	codec := currentDataCodec()
+	if codec == nil {
+		return nil, errors.New("Persisted Point has Data but no DataCodec is registered, call RegisterDataCodec first.")
+	}
+	v, err := codec.DecodeData(b)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// countingWriter wraps an io.Writer to track the total number of bytes
+// written, so WriteTo can report it the way io.WriterTo implementations do.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// countingReader mirrors countingWriter for ReadFrom.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,152 @@
+package ftree
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) EncodeData(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) DecodeData(b []byte) (interface{}, error) {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func TestMarshalUnmarshalBinary(t *testing.T) {
+	RegisterDataCodec(jsonCodec{})
+	defer RegisterDataCodec(nil)
+
+	n := 3
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range center {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 200
+	for i := 0; i < count; i++ {
+		p := new(Point)
+		p.Coords = make([]float64, n)
+		for j := range p.Coords {
+			p.Coords[j] = (rand.Float64() * 2.0) - 1.0
+		}
+		var d interface{} = "payload"
+		p.Data = &d
+		if err = nt.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := nt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Count() != uint64(count) {
+		t.Fatal("round-tripped tree has", loaded.Count(), "points instead of", count)
+	}
+	p1 := make([]float64, n)
+	for i := range p1 {
+		p1[i] = -1
+	}
+	points, err := loaded.Search(p1, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != count {
+		t.Fatal("Search on round-tripped tree returned", len(points), "points instead of", count)
+	}
+	for _, p := range points {
+		if p.Data == nil || (*p.Data).(string) != "payload" {
+			t.Fatal("round-tripped Point lost its Data payload")
+		}
+	}
+}
+
+func TestMarshalUnmarshalBinaryPreservesMaxLeaf(t *testing.T) {
+	center := []float64{0, 0}
+	bounds := []float64{1, 1}
+	pts := []*Point{
+		{Coords: []float64{0.1, 0.1}},
+		{Coords: []float64{0.2, 0.2}},
+		{Coords: []float64{0.3, 0.3}},
+	}
+	nt, err := NewFromPoints(center, bounds, pts, MaxPointsPerLeaf(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nt.children != nil {
+		t.Fatal("test setup expected a single leaf below MaxPointsPerLeaf")
+	}
+
+	data, err := nt.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = loaded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.maxLeaf != 4 {
+		t.Fatal("UnmarshalBinary lost maxLeaf, got", loaded.maxLeaf, "want 4")
+	}
+
+	// a fourth point landing in the same already-populated leaf should
+	// still fit under the original MaxPointsPerLeaf(4) cap, rather than
+	// forcing an immediate subdivide the way a reverted-to-1 cap would.
+	if err = loaded.Add(&Point{Coords: []float64{0.4, 0.4}}); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.children != nil {
+		t.Fatal("Add subdivided a leaf that should still have had room under the original MaxPointsPerLeaf")
+	}
+	if len(loaded.Points()) != 4 {
+		t.Fatal("expected 4 points on the leaf, got", len(loaded.Points()))
+	}
+}
+
+func TestMarshalBinaryWithoutCodecErrors(t *testing.T) {
+	RegisterDataCodec(nil)
+
+	n := 2
+	center := make([]float64, n)
+	bounds := make([]float64, n)
+	for i := range center {
+		bounds[i] = 1
+	}
+	nt, err := New(center, bounds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := new(Point)
+	p.Coords = []float64{0, 0}
+	var d interface{} = 42
+	p.Data = &d
+	if err = nt.Add(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = nt.MarshalBinary(); err == nil {
+		t.Fatal("expected an error marshaling a Point with Data and no registered DataCodec")
+	}
+}